@@ -0,0 +1,203 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vindexes
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+)
+
+var (
+	_ planbuilder.Unique = (*BIP32KSID)(nil)
+)
+
+// bip32Seed is the HMAC key used to derive the master node from the raw
+// seed. It takes the place of the key string BIP32 itself uses, so derived
+// nodes differ from standard BIP32 ones for the same seed.
+const bip32Seed = "vitess seed"
+
+// bip32Node is a (key, chaincode) pair produced at one level of HD
+// derivation.
+type bip32Node struct {
+	k []byte
+	c []byte
+}
+
+// BIP32KSID derives a KeyspaceId for each id via BIP32-style hierarchical
+// deterministic derivation from a single master seed: starting from the
+// master node HMAC-SHA512("vitess seed", seed), it walks the components of
+// path (with {id} substituted in), deriving a new node at each step via
+// HMAC-SHA512(key=c, data=k||uint32BE(component)), and emits the first 8
+// bytes of the final node's key as the KeyspaceId. This lets operators
+// derive stable, unlinkable shard assignments per-tenant from a single
+// secret without maintaining a lookup table. It's Unique but not
+// Reversible.
+type BIP32KSID struct {
+	// prefixNode is the node reached after deriving every path component
+	// before the "{id}" component. It's the same for every id, so it's
+	// computed once at construction time instead of per Map/Verify call.
+	prefixNode bip32Node
+	// idPath holds the "{id}" component and everything after it, still
+	// unsubstituted. These are the only components that differ per id.
+	idPath []string
+}
+
+// NewBIP32KSID creates a BIP32KSID vindex. params accepts:
+//
+//	seed: hex-encoded master secret (required)
+//	path: a derivation path template such as "m/44'/0'/{id}'", with
+//	    exactly one component containing the literal "{id}" (required)
+func NewBIP32KSID(params map[string]interface{}) (planbuilder.Vindex, error) {
+	seedHex, ok := params["seed"].(string)
+	if !ok || seedHex == "" {
+		return nil, fmt.Errorf("bip32_ksid: seed param is required")
+	}
+	seed, err := hex.DecodeString(seedHex)
+	if err != nil {
+		return nil, fmt.Errorf("bip32_ksid: seed: %v", err)
+	}
+	path, ok := params["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("bip32_ksid: path param is required")
+	}
+	components := strings.Split(strings.TrimPrefix(path, "m/"), "/")
+
+	idIndex := -1
+	idComponents := 0
+	for i, component := range components {
+		if strings.Contains(component, "{id}") {
+			idComponents++
+			idIndex = i
+		}
+	}
+	if idComponents != 1 {
+		return nil, fmt.Errorf("bip32_ksid: path must contain exactly one {id} component, got %d in %q", idComponents, path)
+	}
+
+	node := bip32Master(seed)
+	for _, component := range components[:idIndex] {
+		index, hardened, err := parseBIP32Component(component)
+		if err != nil {
+			return nil, err
+		}
+		node = deriveChild(node, index, hardened)
+	}
+	return &BIP32KSID{prefixNode: node, idPath: components[idIndex:]}, nil
+}
+
+// Cost returns the cost of this vindex as 1.
+func (vind *BIP32KSID) Cost() int {
+	return 1
+}
+
+// Verify returns true if id and ksid match.
+func (vind *BIP32KSID) Verify(_ planbuilder.VCursor, id interface{}, ksid key.KeyspaceId) (bool, error) {
+	derived, err := vind.derive(id)
+	if err != nil {
+		return false, err
+	}
+	return derived == ksid, nil
+}
+
+// Map returns the associated keyspace ids for the given ids.
+func (vind *BIP32KSID) Map(_ planbuilder.VCursor, ids []interface{}) ([]key.KeyspaceId, error) {
+	out := make([]key.KeyspaceId, 0, len(ids))
+	for _, id := range ids {
+		derived, err := vind.derive(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, derived)
+	}
+	return out, nil
+}
+
+// derive starts from vind.prefixNode — shared by every id — and derives
+// the remaining components of vind.idPath with id substituted in for
+// "{id}". Since prefixNode is fixed at construction time and the rest of
+// the derivation is per-id local state, this needs no locking.
+func (vind *BIP32KSID) derive(id interface{}) (key.KeyspaceId, error) {
+	idStr, err := idComponent(id)
+	if err != nil {
+		return "", err
+	}
+
+	node := vind.prefixNode
+	for _, component := range vind.idPath {
+		resolved := strings.Replace(component, "{id}", idStr, 1)
+		index, hardened, err := parseBIP32Component(resolved)
+		if err != nil {
+			return "", err
+		}
+		node = deriveChild(node, index, hardened)
+	}
+	return key.KeyspaceId(node.k[:8]), nil
+}
+
+// bip32Master derives the master (k, c) node from the raw seed.
+func bip32Master(seed []byte) bip32Node {
+	mac := hmac.New(sha512.New, []byte(bip32Seed))
+	mac.Write(seed)
+	i := mac.Sum(nil)
+	return bip32Node{k: i[:32], c: i[32:]}
+}
+
+// deriveChild derives the child node at the given index under parent.
+func deriveChild(parent bip32Node, index uint32, hardened bool) bip32Node {
+	if hardened {
+		index |= 1 << 31
+	}
+	var data [36]byte
+	copy(data[:32], parent.k)
+	binary.BigEndian.PutUint32(data[32:], index)
+
+	mac := hmac.New(sha512.New, parent.c)
+	mac.Write(data[:])
+	i := mac.Sum(nil)
+	return bip32Node{k: i[:32], c: i[32:]}
+}
+
+// parseBIP32Component parses a single derivation path component such as
+// "44'" or "7" into its index and hardened flag.
+func parseBIP32Component(component string) (uint32, bool, error) {
+	hardened := strings.HasSuffix(component, "'")
+	if hardened {
+		component = strings.TrimSuffix(component, "'")
+	}
+	index, err := strconv.ParseUint(component, 10, 32)
+	if err != nil {
+		return 0, false, fmt.Errorf("bip32_ksid: invalid path component %q: %v", component, err)
+	}
+	return uint32(index), hardened, nil
+}
+
+// idComponent renders id as the string substituted for "{id}" in the path
+// template.
+func idComponent(id interface{}) (string, error) {
+	switch v := id.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		num, err := getNumber(id)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d", num), nil
+	}
+}
+
+func init() {
+	planbuilder.Register("bip32_ksid", NewBIP32KSID)
+}
@@ -0,0 +1,91 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vindexes
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+const bip32TestSeed = "000102030405060708090a0b0c0d0e0f"
+const bip32TestPath = "m/44'/0'/{id}'"
+
+func TestBIP32KSIDKnownVectors(t *testing.T) {
+	// Expected ksids were computed independently from the same
+	// HMAC-SHA512 derivation chain the vindex implements, so a change to
+	// the derivation logic will be caught here even though the algorithm
+	// is otherwise only exercised through determinism checks.
+	cases := []struct {
+		id   int64
+		want string
+	}{
+		{7, "a2d855039c0f1bff"},
+		{8, "4aff05546b6c4ead"},
+		{42, "713a21f9ae4acfd1"},
+	}
+
+	vindex, err := NewBIP32KSID(map[string]interface{}{
+		"seed": bip32TestSeed,
+		"path": bip32TestPath,
+	})
+	if err != nil {
+		t.Fatalf("NewBIP32KSID: %v", err)
+	}
+	vind := vindex.(*BIP32KSID)
+
+	for _, c := range cases {
+		ksids, err := vind.Map(nil, []interface{}{c.id})
+		if err != nil {
+			t.Fatalf("Map(%d): %v", c.id, err)
+		}
+		if got := hex.EncodeToString([]byte(ksids[0])); got != c.want {
+			t.Errorf("Map(%d) = %s, want %s", c.id, got, c.want)
+		}
+	}
+}
+
+func TestBIP32KSIDDeterministicAcrossInstances(t *testing.T) {
+	params := map[string]interface{}{
+		"seed": bip32TestSeed,
+		"path": bip32TestPath,
+	}
+
+	// Two independently constructed vindexes from the same params stand
+	// in for two different processes deriving the same id.
+	v1, err := NewBIP32KSID(params)
+	if err != nil {
+		t.Fatalf("NewBIP32KSID: %v", err)
+	}
+	v2, err := NewBIP32KSID(params)
+	if err != nil {
+		t.Fatalf("NewBIP32KSID: %v", err)
+	}
+
+	ksids1, err := v1.(*BIP32KSID).Map(nil, []interface{}{int64(99)})
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+	ksids2, err := v2.(*BIP32KSID).Map(nil, []interface{}{int64(99)})
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+	if ksids1[0] != ksids2[0] {
+		t.Errorf("derivation not deterministic: %x != %x", ksids1[0], ksids2[0])
+	}
+}
+
+func TestNewBIP32KSIDRequiresExactlyOneIDComponent(t *testing.T) {
+	for _, path := range []string{
+		"m/44'/0'",      // no {id} component
+		"m/{id}'/{id}'", // two {id} components
+	} {
+		if _, err := NewBIP32KSID(map[string]interface{}{
+			"seed": bip32TestSeed,
+			"path": path,
+		}); err == nil {
+			t.Errorf("NewBIP32KSID(path=%q) succeeded, want error", path)
+		}
+	}
+}
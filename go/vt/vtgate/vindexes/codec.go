@@ -0,0 +1,159 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vindexes
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/youtube/vitess/go/vt/key"
+)
+
+// KeyspaceIdCodec converts between a uint64 and the byte representation
+// stored as a key.KeyspaceId. The numeric vindexes route all conversions
+// through a codec so that users who already store keyspace ids produced by
+// other systems (protobuf-style varint counters, little-endian on-disk
+// formats, ...) can plug Vitess in without reformatting their data.
+type KeyspaceIdCodec interface {
+	Encode(uint64) key.KeyspaceId
+	Decode(key.KeyspaceId) (uint64, error)
+}
+
+// codecRegistryMu guards codecRegistry, since RegisterCodec can run
+// concurrently with vindex construction (and thus LookupCodec) once the
+// server is up.
+var codecRegistryMu sync.RWMutex
+
+// codecRegistry holds the named codecs available to the "codec" vindex
+// param. It's seeded with the built-in codecs and can be extended from
+// outside this package via RegisterCodec. Guarded by codecRegistryMu.
+var codecRegistry = map[string]KeyspaceIdCodec{
+	"bigendian":    bigEndianCodec{},
+	"littleendian": littleEndianCodec{},
+	"varint":       varintCodec{},
+}
+
+// RegisterCodec makes codec available to numeric vindexes under name, so
+// that packages outside vindexes can add their own encodings without
+// modifying this package.
+func RegisterCodec(name string, codec KeyspaceIdCodec) {
+	codecRegistryMu.Lock()
+	defer codecRegistryMu.Unlock()
+	codecRegistry[name] = codec
+}
+
+// LookupCodec returns the codec registered under name.
+func LookupCodec(name string) (KeyspaceIdCodec, error) {
+	codecRegistryMu.RLock()
+	defer codecRegistryMu.RUnlock()
+	codec, ok := codecRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+	return codec, nil
+}
+
+// codecFromParams returns the codec named by the "codec" param, or def if
+// the param is absent.
+func codecFromParams(params map[string]interface{}, def KeyspaceIdCodec) (KeyspaceIdCodec, error) {
+	name, ok := params["codec"]
+	if !ok {
+		return def, nil
+	}
+	n, ok := name.(string)
+	if !ok {
+		return nil, fmt.Errorf("codec param must be a string")
+	}
+	return LookupCodec(n)
+}
+
+// bigEndianCodec encodes a uint64 as 8 big-endian bytes. It's the default
+// codec, matching NumKSID's historical byte layout.
+type bigEndianCodec struct{}
+
+func (bigEndianCodec) Encode(num uint64) key.KeyspaceId {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], num)
+	return key.KeyspaceId(b[:])
+}
+
+func (bigEndianCodec) Decode(ksid key.KeyspaceId) (uint64, error) {
+	if len(ksid) != 8 {
+		return 0, fmt.Errorf("bigendian codec: ksid has %d bytes, want 8", len(ksid))
+	}
+	return binary.BigEndian.Uint64([]byte(ksid)), nil
+}
+
+// littleEndianCodec encodes a uint64 as 8 little-endian bytes.
+type littleEndianCodec struct{}
+
+func (littleEndianCodec) Encode(num uint64) key.KeyspaceId {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], num)
+	return key.KeyspaceId(b[:])
+}
+
+func (littleEndianCodec) Decode(ksid key.KeyspaceId) (uint64, error) {
+	if len(ksid) != 8 {
+		return 0, fmt.Errorf("littleendian codec: ksid has %d bytes, want 8", len(ksid))
+	}
+	return binary.LittleEndian.Uint64([]byte(ksid)), nil
+}
+
+// varintCodec encodes a uint64 as a protobuf-style base-128 varint, for
+// users who already store counters produced that way.
+type varintCodec struct{}
+
+func (varintCodec) Encode(num uint64) key.KeyspaceId {
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(b, num)
+	return key.KeyspaceId(b[:n])
+}
+
+func (varintCodec) Decode(ksid key.KeyspaceId) (uint64, error) {
+	num, n := binary.Uvarint([]byte(ksid))
+	if n <= 0 {
+		return 0, fmt.Errorf("varint codec: invalid ksid")
+	}
+	return num, nil
+}
+
+// fixedWidthCodec encodes a uint64 into a configurable number of bytes in
+// a configurable byte order, truncating high-order bytes on Encode and
+// zero-extending on Decode. NumKSIDN uses this to support ids narrower
+// than 64 bits.
+type fixedWidthCodec struct {
+	bytes int
+	order binary.ByteOrder
+}
+
+// NewFixedWidthCodec returns a KeyspaceIdCodec that encodes a uint64 into
+// the given number of bytes (1..8) using order.
+func NewFixedWidthCodec(bytes int, order binary.ByteOrder) KeyspaceIdCodec {
+	return fixedWidthCodec{bytes: bytes, order: order}
+}
+
+func (c fixedWidthCodec) Encode(num uint64) key.KeyspaceId {
+	var full [8]byte
+	c.order.PutUint64(full[:], num)
+	if c.order == binary.BigEndian {
+		return key.KeyspaceId(full[8-c.bytes:])
+	}
+	return key.KeyspaceId(full[:c.bytes])
+}
+
+func (c fixedWidthCodec) Decode(ksid key.KeyspaceId) (uint64, error) {
+	if len(ksid) != c.bytes {
+		return 0, fmt.Errorf("fixedwidth codec: ksid has %d bytes, want %d", len(ksid), c.bytes)
+	}
+	var full [8]byte
+	if c.order == binary.BigEndian {
+		copy(full[8-c.bytes:], ksid)
+	} else {
+		copy(full[:c.bytes], ksid)
+	}
+	return c.order.Uint64(full[:]), nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vindexes
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+)
+
+var (
+	_ planbuilder.Unique = (*HashKSID)(nil)
+)
+
+// hashAlgorithm identifies the digest function HashKSID hashes an id with
+// before truncating it to a KeyspaceId.
+type hashAlgorithm string
+
+const (
+	hashSHA256     hashAlgorithm = "sha256"
+	hashBLAKE2b    hashAlgorithm = "blake2b"
+	hashLegacy3DES hashAlgorithm = "legacy3des"
+)
+
+// HashKSID defines a vindex that maps an id to a KeyspaceId by hashing it
+// with a configurable algorithm and keeping the first N bytes of the
+// digest. Unlike Hash, it isn't tied to the legacy 3DES construction and
+// gives a better-distributed shard key for keyspaces that don't need to
+// stay compatible with it. It's Unique but not Reversible.
+type HashKSID struct {
+	algorithm hashAlgorithm
+	bytes     int
+	salt      []byte
+}
+
+// NewHashKSID creates a HashKSID vindex. params accepts:
+//
+//	algorithm: "sha256" (default), "blake2b", or "legacy3des" (delegates to
+//	    the existing 3DES-based Hash vindex, for compatibility)
+//	bytes: number of leading digest bytes to keep as the KeyspaceId
+//	    (default 8)
+//	salt: an optional string mixed into the digest so that multiple
+//	    HashKSID vindexes don't collide on the same ids
+func NewHashKSID(params map[string]interface{}) (planbuilder.Vindex, error) {
+	vind := &HashKSID{
+		algorithm: hashSHA256,
+		bytes:     8,
+	}
+	if alg, ok := params["algorithm"]; ok {
+		a, ok := alg.(string)
+		if !ok {
+			return nil, fmt.Errorf("hash_ksid: algorithm must be a string")
+		}
+		switch hashAlgorithm(a) {
+		case hashSHA256, hashBLAKE2b, hashLegacy3DES:
+			vind.algorithm = hashAlgorithm(a)
+		default:
+			return nil, fmt.Errorf("hash_ksid: unknown algorithm %q", a)
+		}
+	}
+	if nb, ok := params["bytes"]; ok {
+		n, err := toInt(nb)
+		if err != nil {
+			return nil, fmt.Errorf("hash_ksid: bytes: %v", err)
+		}
+		if n < 1 || n > 8 {
+			return nil, fmt.Errorf("hash_ksid: bytes must be between 1 and 8, got %d", n)
+		}
+		vind.bytes = n
+	}
+	if salt, ok := params["salt"]; ok {
+		s, ok := salt.(string)
+		if !ok {
+			return nil, fmt.Errorf("hash_ksid: salt must be a string")
+		}
+		vind.salt = []byte(s)
+	}
+	if vind.algorithm == hashLegacy3DES {
+		// legacy3des just delegates to the existing Hash vindex, which has
+		// its own fixed 3DES construction and byte layout: salt and bytes
+		// would be silently ignored, so reject them up front instead of
+		// letting a configured param do nothing.
+		if _, ok := params["salt"]; ok {
+			return nil, fmt.Errorf("hash_ksid: salt isn't supported with algorithm %q", hashLegacy3DES)
+		}
+		if _, ok := params["bytes"]; ok {
+			return nil, fmt.Errorf("hash_ksid: bytes isn't supported with algorithm %q", hashLegacy3DES)
+		}
+	}
+	return vind, nil
+}
+
+// Cost returns the cost of this vindex as 1.
+func (vind *HashKSID) Cost() int {
+	return 1
+}
+
+// Verify returns true if id and ksid match.
+func (vind *HashKSID) Verify(_ planbuilder.VCursor, id interface{}, ksid key.KeyspaceId) (bool, error) {
+	computed, err := vind.hash(id)
+	if err != nil {
+		return false, err
+	}
+	return computed == ksid, nil
+}
+
+// Map returns the associated keyspace ids for the given ids.
+func (vind *HashKSID) Map(_ planbuilder.VCursor, ids []interface{}) ([]key.KeyspaceId, error) {
+	out := make([]key.KeyspaceId, 0, len(ids))
+	for _, id := range ids {
+		ksid, err := vind.hash(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ksid)
+	}
+	return out, nil
+}
+
+// hash computes the KeyspaceId for id, handling both numeric and
+// string/[]byte ids uniformly.
+func (vind *HashKSID) hash(id interface{}) (key.KeyspaceId, error) {
+	if vind.algorithm == hashLegacy3DES {
+		h := Hash{}
+		ksids, err := h.Map(nil, []interface{}{id})
+		if err != nil {
+			return "", err
+		}
+		return ksids[0], nil
+	}
+
+	b, err := idBytes(id)
+	if err != nil {
+		return "", err
+	}
+	data := append(append([]byte(nil), vind.salt...), b...)
+
+	switch vind.algorithm {
+	case hashBLAKE2b:
+		sum := blake2b.Sum512(data)
+		return key.KeyspaceId(sum[:vind.bytes]), nil
+	default:
+		sum := sha256.Sum256(data)
+		return key.KeyspaceId(sum[:vind.bytes]), nil
+	}
+}
+
+// idBytes returns the canonical byte representation of an id, whether it
+// was passed in as a string, []byte, or a number.
+func idBytes(id interface{}) ([]byte, error) {
+	switch v := id.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		num, err := getNumber(id)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(fmt.Sprintf("%d", num)), nil
+	}
+}
+
+// toInt coerces a params value to an int.
+func toInt(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func init() {
+	planbuilder.Register("hash_ksid", NewHashKSID)
+}
@@ -6,6 +6,7 @@ package vindexes
 
 import (
 	"encoding/binary"
+	"fmt"
 
 	"github.com/youtube/vitess/go/vt/key"
 	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
@@ -16,51 +17,220 @@ var (
 	_ planbuilder.Reversible = NumKSID{}
 )
 
-// NumKSID defines a bit-pattern mapping of a uint64 to the KeyspaceId.
-// It's Unique and Reversible.
-type NumKSID struct{}
+// NumKSID defines a bit-pattern mapping of a uint64 to the KeyspaceId,
+// routed through a KeyspaceIdCodec. It's Unique and Reversible.
+type NumKSID struct {
+	codec KeyspaceIdCodec
+}
 
-// NewNumKSID creates a NumKSID vindex.
-func NewNumKSID(_ map[string]interface{}) (planbuilder.Vindex, error) {
-	return NumKSID{}, nil
+// NewNumKSID creates a NumKSID vindex. params accepts:
+//
+//	codec: name of a registered KeyspaceIdCodec (default "bigendian", for
+//	    backward compatibility)
+func NewNumKSID(params map[string]interface{}) (planbuilder.Vindex, error) {
+	codec, err := codecFromParams(params, bigEndianCodec{})
+	if err != nil {
+		return nil, fmt.Errorf("num_ksid: %v", err)
+	}
+	return NumKSID{codec: codec}, nil
 }
 
 // Cost returns the cost of this vindex as 0.
-func (_ NumKSID) Cost() int {
+func (vind NumKSID) Cost() int {
 	return 0
 }
 
 // Verify returns true if id and ksid match.
-func (_ NumKSID) Verify(_ planbuilder.VCursor, id interface{}, ksid key.KeyspaceId) (bool, error) {
-	var keybytes [8]byte
+func (vind NumKSID) Verify(_ planbuilder.VCursor, id interface{}, ksid key.KeyspaceId) (bool, error) {
 	num, err := getNumber(id)
 	if err != nil {
 		return false, err
 	}
-	binary.BigEndian.PutUint64(keybytes[:], uint64(num))
-	return key.KeyspaceId(keybytes[:]) == ksid, nil
+	return vind.codec.Encode(uint64(num)) == ksid, nil
 }
 
 // Map returns the associated keyspae ids for the given ids.
-func (_ NumKSID) Map(_ planbuilder.VCursor, ids []interface{}) ([]key.KeyspaceId, error) {
-	var keybytes [8]byte
+func (vind NumKSID) Map(_ planbuilder.VCursor, ids []interface{}) ([]key.KeyspaceId, error) {
 	out := make([]key.KeyspaceId, 0, len(ids))
 	for _, id := range ids {
 		num, err := getNumber(id)
 		if err != nil {
 			return nil, err
 		}
-		binary.BigEndian.PutUint64(keybytes[:], uint64(num))
-		out = append(out, key.KeyspaceId(keybytes[:]))
+		out = append(out, vind.codec.Encode(uint64(num)))
 	}
 	return out, nil
 }
 
 // ReverseMap returns the associated id for the ksid.
-func (_ NumKSID) ReverseMap(_ planbuilder.VCursor, ksid key.KeyspaceId) (interface{}, error) {
-	return binary.BigEndian.Uint64([]byte(ksid)), nil
+func (vind NumKSID) ReverseMap(_ planbuilder.VCursor, ksid key.KeyspaceId) (interface{}, error) {
+	return vind.codec.Decode(ksid)
 }
 
 func init() {
 	planbuilder.Register("num_ksid", NewNumKSID)
 }
+
+var (
+	_ planbuilder.Unique     = NumKSIDLittleEndian{}
+	_ planbuilder.Reversible = NumKSIDLittleEndian{}
+)
+
+// NumKSIDLittleEndian is NumKSID with the bytes reversed: by default it maps
+// a uint64 to the KeyspaceId using little-endian byte order instead of
+// big-endian.
+// It's Unique and Reversible.
+type NumKSIDLittleEndian struct {
+	codec KeyspaceIdCodec
+}
+
+// NewNumKSIDLittleEndian creates a NumKSIDLittleEndian vindex. params
+// accepts a "codec" param like NewNumKSID, defaulting to "littleendian".
+func NewNumKSIDLittleEndian(params map[string]interface{}) (planbuilder.Vindex, error) {
+	codec, err := codecFromParams(params, littleEndianCodec{})
+	if err != nil {
+		return nil, fmt.Errorf("num_ksid_little_endian: %v", err)
+	}
+	return NumKSIDLittleEndian{codec: codec}, nil
+}
+
+// Cost returns the cost of this vindex as 0.
+func (vind NumKSIDLittleEndian) Cost() int {
+	return 0
+}
+
+// Verify returns true if id and ksid match.
+func (vind NumKSIDLittleEndian) Verify(_ planbuilder.VCursor, id interface{}, ksid key.KeyspaceId) (bool, error) {
+	num, err := getNumber(id)
+	if err != nil {
+		return false, err
+	}
+	return vind.codec.Encode(uint64(num)) == ksid, nil
+}
+
+// Map returns the associated keyspae ids for the given ids.
+func (vind NumKSIDLittleEndian) Map(_ planbuilder.VCursor, ids []interface{}) ([]key.KeyspaceId, error) {
+	out := make([]key.KeyspaceId, 0, len(ids))
+	for _, id := range ids {
+		num, err := getNumber(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vind.codec.Encode(uint64(num)))
+	}
+	return out, nil
+}
+
+// ReverseMap returns the associated id for the ksid.
+func (vind NumKSIDLittleEndian) ReverseMap(_ planbuilder.VCursor, ksid key.KeyspaceId) (interface{}, error) {
+	return vind.codec.Decode(ksid)
+}
+
+func init() {
+	planbuilder.Register("num_ksid_little_endian", NewNumKSIDLittleEndian)
+}
+
+var (
+	_ planbuilder.Unique     = (*NumKSIDN)(nil)
+	_ planbuilder.Reversible = (*NumKSIDN)(nil)
+)
+
+// NumKSIDN is a parameterized version of NumKSID: it maps a uint64 to a
+// KeyspaceId of a configurable width and byte order. It's useful for users
+// who shard on ids narrower than 64 bits, or who want to co-locate ranges
+// differently than a full-width big-endian uint64 allows. It's Unique and
+// Reversible.
+type NumKSIDN struct {
+	codec KeyspaceIdCodec
+}
+
+// NewNumKSIDN creates a NumKSIDN vindex. params accepts either:
+//
+//	codec: name of a registered KeyspaceIdCodec, which takes precedence
+//	    over bytes/endian below
+//
+// or:
+//
+//	bytes: number of bytes of KeyspaceId to produce, 1..8 (default 8)
+//	endian: "big" (default) or "little"
+func NewNumKSIDN(params map[string]interface{}) (planbuilder.Vindex, error) {
+	if name, ok := params["codec"]; ok {
+		n, ok := name.(string)
+		if !ok {
+			return nil, fmt.Errorf("num_ksid_n: codec param must be a string")
+		}
+		codec, err := LookupCodec(n)
+		if err != nil {
+			return nil, fmt.Errorf("num_ksid_n: %v", err)
+		}
+		return &NumKSIDN{codec: codec}, nil
+	}
+
+	bytes := 8
+	if nb, ok := params["bytes"]; ok {
+		n, err := toInt(nb)
+		if err != nil {
+			return nil, fmt.Errorf("num_ksid_n: bytes: %v", err)
+		}
+		if n < 1 || n > 8 {
+			return nil, fmt.Errorf("num_ksid_n: bytes must be between 1 and 8, got %d", n)
+		}
+		bytes = n
+	}
+	order := binary.ByteOrder(binary.BigEndian)
+	if end, ok := params["endian"]; ok {
+		e, ok := end.(string)
+		if !ok {
+			return nil, fmt.Errorf("num_ksid_n: endian must be a string")
+		}
+		switch e {
+		case "big":
+			order = binary.BigEndian
+		case "little":
+			order = binary.LittleEndian
+		default:
+			return nil, fmt.Errorf("num_ksid_n: unknown endian %q", e)
+		}
+	}
+	return &NumKSIDN{codec: NewFixedWidthCodec(bytes, order)}, nil
+}
+
+// Cost returns the cost of this vindex as 0.
+func (vind *NumKSIDN) Cost() int {
+	return 0
+}
+
+// Verify returns true if id and ksid match. A ksid whose length doesn't
+// match the configured width never matches, since id is always encoded at
+// that width before comparing.
+func (vind *NumKSIDN) Verify(_ planbuilder.VCursor, id interface{}, ksid key.KeyspaceId) (bool, error) {
+	num, err := getNumber(id)
+	if err != nil {
+		return false, err
+	}
+	return vind.codec.Encode(uint64(num)) == ksid, nil
+}
+
+// Map returns the associated keyspace ids for the given ids.
+func (vind *NumKSIDN) Map(_ planbuilder.VCursor, ids []interface{}) ([]key.KeyspaceId, error) {
+	out := make([]key.KeyspaceId, 0, len(ids))
+	for _, id := range ids {
+		num, err := getNumber(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vind.codec.Encode(uint64(num)))
+	}
+	return out, nil
+}
+
+// ReverseMap returns the associated id for the ksid as a uint64. With the
+// bytes/endian params, a ksid of the configured width is zero-extended to
+// 64 bits, and a ksid of any other length is an error.
+func (vind *NumKSIDN) ReverseMap(_ planbuilder.VCursor, ksid key.KeyspaceId) (interface{}, error) {
+	return vind.codec.Decode(ksid)
+}
+
+func init() {
+	planbuilder.Register("num_ksid_n", NewNumKSIDN)
+}
@@ -0,0 +1,100 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vindexes
+
+import (
+	"fmt"
+
+	"github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+)
+
+var (
+	_ planbuilder.NonUnique  = NumKSIDRange{}
+	_ planbuilder.Reversible = NumKSIDRange{}
+)
+
+// NumKSIDRange groups consecutive numeric ids into 2^shift buckets that
+// share a KeyspaceId prefix, so that ranges of sequentially-issued ids
+// (e.g. auto-increment user ids) can be co-located on the same shard. Its
+// byte layout on the wire is identical to NumKSID's. It's NonUnique and
+// Reversible.
+type NumKSIDRange struct {
+	shift uint
+	codec KeyspaceIdCodec
+}
+
+// NewNumKSIDRange creates a NumKSIDRange vindex. params accepts:
+//
+//	shift: number of low bits that are cleared to compute the bucket,
+//	    0..63 (required)
+//	codec: name of a registered KeyspaceIdCodec (default "bigendian", to
+//	    stay compatible with NumKSID's byte layout)
+func NewNumKSIDRange(params map[string]interface{}) (planbuilder.Vindex, error) {
+	s, ok := params["shift"]
+	if !ok {
+		return nil, fmt.Errorf("num_ksid_range: shift param is required")
+	}
+	shift, err := toInt(s)
+	if err != nil {
+		return nil, fmt.Errorf("num_ksid_range: shift: %v", err)
+	}
+	if shift < 0 || shift > 63 {
+		return nil, fmt.Errorf("num_ksid_range: shift must be between 0 and 63, got %d", shift)
+	}
+	codec, err := codecFromParams(params, bigEndianCodec{})
+	if err != nil {
+		return nil, fmt.Errorf("num_ksid_range: %v", err)
+	}
+	return NumKSIDRange{shift: uint(shift), codec: codec}, nil
+}
+
+// Cost returns the cost of this vindex as 1.
+func (_ NumKSIDRange) Cost() int {
+	return 1
+}
+
+// Verify returns true if id's bucket matches ksid.
+func (vind NumKSIDRange) Verify(_ planbuilder.VCursor, id interface{}, ksid key.KeyspaceId) (bool, error) {
+	bucketed, err := vind.bucket(id)
+	if err != nil {
+		return false, err
+	}
+	return bucketed == ksid, nil
+}
+
+// Map returns, for each id, all the KeyspaceIds in its bucket. Since every
+// id in a bucket hashes to the same prefix, each inner slice has exactly
+// one element.
+func (vind NumKSIDRange) Map(_ planbuilder.VCursor, ids []interface{}) ([][]key.KeyspaceId, error) {
+	out := make([][]key.KeyspaceId, 0, len(ids))
+	for _, id := range ids {
+		bucketed, err := vind.bucket(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, []key.KeyspaceId{bucketed})
+	}
+	return out, nil
+}
+
+// ReverseMap returns the bucket's low id for the given ksid.
+func (vind NumKSIDRange) ReverseMap(_ planbuilder.VCursor, ksid key.KeyspaceId) (interface{}, error) {
+	return vind.codec.Decode(ksid)
+}
+
+// bucket computes the KeyspaceId for the bucket that id falls in.
+func (vind NumKSIDRange) bucket(id interface{}) (key.KeyspaceId, error) {
+	num, err := getNumber(id)
+	if err != nil {
+		return "", err
+	}
+	bucketed := (uint64(num) >> vind.shift) << vind.shift
+	return vind.codec.Encode(bucketed), nil
+}
+
+func init() {
+	planbuilder.Register("num_ksid_range", NewNumKSIDRange)
+}
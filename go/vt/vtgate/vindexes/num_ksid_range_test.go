@@ -0,0 +1,46 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vindexes
+
+import (
+	"testing"
+)
+
+func TestNumKSIDRangeBucketsSharePrefix(t *testing.T) {
+	vindex, err := NewNumKSIDRange(map[string]interface{}{"shift": 4})
+	if err != nil {
+		t.Fatalf("NewNumKSIDRange: %v", err)
+	}
+	vind := vindex.(NumKSIDRange)
+
+	// 100 (0b01100100) and 103 (0b01100111) differ only in the low 4 bits,
+	// so with shift=4 they must land in the same bucket.
+	got, err := vind.Map(nil, []interface{}{int64(100), int64(103)})
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+	ksidA, ksidB := got[0][0], got[1][0]
+	if ksidA != ksidB {
+		t.Errorf("Map(100) = %v, Map(103) = %v, want equal ksid prefixes", ksidA, ksidB)
+	}
+
+	// 100 and 116 (0b01110100) differ in bit 4, outside the low 4 bits, so
+	// they must land in different buckets.
+	other, err := vind.Map(nil, []interface{}{int64(116)})
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+	if ksidA == other[0][0] {
+		t.Errorf("Map(100) and Map(116) produced the same ksid, want different buckets")
+	}
+
+	id, err := vind.ReverseMap(nil, ksidA)
+	if err != nil {
+		t.Fatalf("ReverseMap: %v", err)
+	}
+	if want := uint64(96); id.(uint64) != want {
+		t.Errorf("ReverseMap(bucket(100)) = %d, want %d", id, want)
+	}
+}
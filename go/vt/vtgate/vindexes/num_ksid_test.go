@@ -0,0 +1,108 @@
+// Copyright 2014, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vindexes
+
+import (
+	"testing"
+
+	"github.com/youtube/vitess/go/vt/key"
+)
+
+func TestNumKSIDNWrapAround(t *testing.T) {
+	vindex, err := NewNumKSIDN(map[string]interface{}{"bytes": 1})
+	if err != nil {
+		t.Fatalf("NewNumKSIDN: %v", err)
+	}
+	vind := vindex.(*NumKSIDN)
+
+	cases := []struct {
+		id   int64
+		want string
+	}{
+		{0, "\x00"},
+		{255, "\xff"},
+		{256, "\x00"}, // wraps: only the low byte survives
+		{257, "\x01"},
+	}
+	for _, c := range cases {
+		ksids, err := vind.Map(nil, []interface{}{c.id})
+		if err != nil {
+			t.Fatalf("Map(%d): %v", c.id, err)
+		}
+		if got := string(ksids[0]); got != c.want {
+			t.Errorf("Map(%d) = %q, want %q", c.id, got, c.want)
+		}
+	}
+}
+
+func TestNumKSIDNNegativeSigned(t *testing.T) {
+	vindex, err := NewNumKSIDN(map[string]interface{}{"bytes": 2})
+	if err != nil {
+		t.Fatalf("NewNumKSIDN: %v", err)
+	}
+	vind := vindex.(*NumKSIDN)
+
+	// uint64(-1) is all ones, truncated to the low 2 bytes.
+	ksids, err := vind.Map(nil, []interface{}{int64(-1)})
+	if err != nil {
+		t.Fatalf("Map(-1): %v", err)
+	}
+	if want := "\xff\xff"; string(ksids[0]) != want {
+		t.Errorf("Map(-1) = %q, want %q", ksids[0], want)
+	}
+
+	ok, err := vind.Verify(nil, int64(-1), ksids[0])
+	if err != nil {
+		t.Fatalf("Verify(-1): %v", err)
+	}
+	if !ok {
+		t.Errorf("Verify(-1, %q) = false, want true", ksids[0])
+	}
+}
+
+func TestNumKSIDNShortAndLongKsid(t *testing.T) {
+	vindex, err := NewNumKSIDN(map[string]interface{}{"bytes": 4})
+	if err != nil {
+		t.Fatalf("NewNumKSIDN: %v", err)
+	}
+	vind := vindex.(*NumKSIDN)
+
+	for _, ksid := range []key.KeyspaceId{
+		key.KeyspaceId("\x00\x00\x00"),         // too short
+		key.KeyspaceId("\x00\x00\x00\x00\x00"), // too long
+	} {
+		// A ksid of the wrong width can never have been produced by Map,
+		// so Verify must report it as a non-match rather than comparing
+		// against a decoded, zero-extended value.
+		if ok, err := vind.Verify(nil, int64(1), ksid); err != nil || ok {
+			t.Errorf("Verify with %d-byte ksid = (%v, %v), want (false, nil)", len(ksid), ok, err)
+		}
+		if _, err := vind.ReverseMap(nil, ksid); err == nil {
+			t.Errorf("ReverseMap with %d-byte ksid succeeded, want error", len(ksid))
+		}
+	}
+}
+
+func TestNumKSIDLittleEndianRoundTrip(t *testing.T) {
+	vindex, err := NewNumKSIDLittleEndian(nil)
+	if err != nil {
+		t.Fatalf("NewNumKSIDLittleEndian: %v", err)
+	}
+	vind := vindex.(NumKSIDLittleEndian)
+
+	for _, id := range []int64{0, 1, -1, 1 << 40} {
+		ksids, err := vind.Map(nil, []interface{}{id})
+		if err != nil {
+			t.Fatalf("Map(%d): %v", id, err)
+		}
+		got, err := vind.ReverseMap(nil, ksids[0])
+		if err != nil {
+			t.Fatalf("ReverseMap(%d): %v", id, err)
+		}
+		if want := uint64(id); got.(uint64) != want {
+			t.Errorf("ReverseMap(Map(%d)) = %d, want %d", id, got, want)
+		}
+	}
+}